@@ -7,49 +7,59 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
-
-	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
 )
 
 type DatabaseConfig struct {
 	Database string `json:"database"`
 	User     string `json:"user"`
 	Password string `json:"password"`
+	// Extensions are Postgres extensions to CREATE EXTENSION IF NOT EXISTS
+	// inside the target database (e.g. "pgcrypto", "pg_stat_statements").
+	// Ignored by backends other than Postgres.
+	Extensions []string `json:"extensions,omitempty"`
+	// Schemas are additional schemas to create, owned by User. Ignored by
+	// backends other than Postgres.
+	Schemas []string `json:"schemas,omitempty"`
+	// Privileges grants fine-grained per-role access narrower than full
+	// database ownership, e.g. a read-only role. Ignored by backends other
+	// than Postgres.
+	Privileges []RolePrivilege `json:"privileges,omitempty"`
+}
+
+// RolePrivilege describes the access one role should have within a single
+// schema, short of owning the database outright - e.g. CONNECT + USAGE +
+// SELECT for a read-only reporting user.
+type RolePrivilege struct {
+	Role   string `json:"role"`
+	Schema string `json:"schema"`
+	// Privileges are keywords such as "CONNECT", "USAGE", "SELECT",
+	// "INSERT", "UPDATE", "DELETE". CONNECT and USAGE are granted directly;
+	// the rest are granted on all tables in Schema and carried forward to
+	// future tables via ALTER DEFAULT PRIVILEGES.
+	Privileges []string `json:"privileges"`
 }
 
 type DatabaseServer struct {
 	Name                 string           `json:"name"`
 	RootConnectionString string           `json:"root_connection_string"`
 	Databases            []DatabaseConfig `json:"databases"`
+	// Prune, when true, drops databases and users found on this server
+	// that are no longer declared in Databases.
+	Prune bool `json:"prune,omitempty"`
 }
 
 type Config struct {
 	Servers []DatabaseServer `json:"servers"`
 }
 
-type DBType int
-
-const (
-	PostgreSQL DBType = iota
-	MariaDB
-)
-
-func detectDBType(connStr string) DBType {
-	if strings.HasPrefix(connStr, "mariadb://") || strings.HasPrefix(connStr, "mysql://") {
-		return MariaDB
-	}
-	return PostgreSQL
-}
-
 func main() {
 	log.Println("PostgreSQL Database Provisioner starting...")
 
 	// Check if running in watch mode
 	watchMode := os.Getenv("WATCH_MODE")
 	if watchMode == "true" {
+		startMetricsServer()
 		runWatchMode()
 	} else {
 		runOnce()
@@ -69,45 +79,6 @@ func runOnce() {
 	log.Println("Database provisioning completed")
 }
 
-func runWatchMode() {
-	log.Println("Running in WATCH MODE - will monitor config file for changes")
-	
-	configPath := getConfigPath()
-	var lastModTime time.Time
-	checkInterval := 10 * time.Second
-
-	for {
-		fileInfo, err := os.Stat(configPath)
-		if err != nil {
-			log.Printf("Error checking config file: %v", err)
-			time.Sleep(checkInterval)
-			continue
-		}
-
-		currentModTime := fileInfo.ModTime()
-		
-		if currentModTime.After(lastModTime) {
-			log.Println("Config file changed, reprocessing...")
-			lastModTime = currentModTime
-
-			config, err := loadConfig()
-			if err != nil {
-				log.Printf("Failed to load config: %v", err)
-				time.Sleep(checkInterval)
-				continue
-			}
-
-			if err := processConfig(config); err != nil {
-				log.Printf("Failed to process config: %v", err)
-			} else {
-				log.Println("Config processed successfully")
-			}
-		}
-
-		time.Sleep(checkInterval)
-	}
-}
-
 func getConfigPath() string {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -118,7 +89,7 @@ func getConfigPath() string {
 
 func loadConfig() (*Config, error) {
 	configPath := getConfigPath()
-	
+
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -143,6 +114,10 @@ func loadConfig() (*Config, error) {
 		}
 	}
 
+	if err := resolveConfigSecrets(context.Background(), &config); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -158,58 +133,15 @@ func processConfig(config *Config) error {
 		log.Printf("Processing server: %s", serverName)
 		log.Printf("========================================")
 
-		// Detect database type
-		dbType := detectDBType(server.RootConnectionString)
-		
-		var connStr string
-		if dbType == MariaDB {
-			// Convert mariadb:// to mysql:// for the driver
-			connStr = strings.Replace(server.RootConnectionString, "mariadb://", "mysql://", 1)
-			log.Printf("Detected MariaDB/MySQL connection for %s", serverName)
-		} else {
-			connStr = server.RootConnectionString
-			log.Printf("Detected PostgreSQL connection for %s", serverName)
-		}
+		start := time.Now()
+		err := processServer(server, serverName)
+		observeReconcile(serverName, err, time.Since(start))
 
-		// Connect to database as root
-		var db *sql.DB
-		var err error
-		
-		if dbType == MariaDB {
-			db, err = connectWithRetry("mysql", connStr, 5, 5*time.Second)
-		} else {
-			db, err = connectWithRetry("postgres", connStr, 5, 5*time.Second)
-		}
-		
 		if err != nil {
-			log.Printf("Failed to connect to %s: %v", serverName, err)
-			log.Printf("Skipping server: %s", serverName)
-			continue
-		}
-
-		log.Printf("Connected to %s successfully", serverName)
-
-		// Process each database configuration for this server
-		for i, dbConfig := range server.Databases {
-			log.Printf("Processing database %d/%d on %s: %s", i+1, len(server.Databases), serverName, dbConfig.Database)
-
-			if dbType == MariaDB {
-				if err := provisionMariaDB(db, dbConfig); err != nil {
-					log.Printf("Failed to provision database %s on %s: %v", dbConfig.Database, serverName, err)
-					continue
-				}
-			} else {
-				if err := provisionPostgreSQL(db, dbConfig); err != nil {
-					log.Printf("Failed to provision database %s on %s: %v", dbConfig.Database, serverName, err)
-					continue
-				}
-			}
-
-			log.Printf("Successfully provisioned database: %s with user: %s on %s", dbConfig.Database, dbConfig.User, serverName)
+			log.Printf("Failed to process server %s: %v", serverName, err)
+		} else {
+			log.Printf("Completed processing server: %s", serverName)
 		}
-
-		db.Close()
-		log.Printf("Completed processing server: %s", serverName)
 	}
 
 	log.Printf("========================================")
@@ -219,204 +151,111 @@ func processConfig(config *Config) error {
 	return nil
 }
 
-func connectWithRetry(driverName, connStr string, maxRetries int, delay time.Duration) (*sql.DB, error) {
-	var db *sql.DB
-	var err error
-
-	for i := 0; i < maxRetries; i++ {
-		db, err = sql.Open(driverName, connStr)
-		if err != nil {
-			log.Printf("Attempt %d/%d: Failed to open connection: %v", i+1, maxRetries, err)
-			time.Sleep(delay)
-			continue
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		err = db.PingContext(ctx)
-		cancel()
+func processServer(server DatabaseServer, serverName string) error {
+	// Detect database type and look up its provisioner
+	dbType := detectDBType(server.RootConnectionString)
+	provisioner, err := provisionerFor(dbType)
+	if err != nil {
+		return err
+	}
+	log.Printf("Detected %s connection for %s", dbType, serverName)
 
-		if err == nil {
-			return db, nil
-		}
+	connStr := provisioner.NormalizeConnectionString(server.RootConnectionString)
 
-		log.Printf("Attempt %d/%d: Failed to ping database: %v", i+1, maxRetries, err)
-		db.Close()
-		time.Sleep(delay)
+	// Connect to database as root
+	db, err := connectWithRetry(provisioner.DriverName(), connStr, 5, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
 	}
+	defer db.Close()
 
-	return nil, fmt.Errorf("failed to connect after %d attempts: %w", maxRetries, err)
-}
+	log.Printf("Connected to %s successfully", serverName)
 
-func provisionPostgreSQL(db *sql.DB, config DatabaseConfig) error {
 	ctx := context.Background()
-
-	// Check if user exists
-	userExists, err := checkPostgreSQLUserExists(ctx, db, config.User)
+	actualDatabases, databaseOwners, actualUsers, err := provisioner.ListState(ctx, db)
 	if err != nil {
-		return fmt.Errorf("failed to check user existence: %w", err)
+		return fmt.Errorf("failed to read actual state: %w", err)
 	}
 
-	// Create user if it doesn't exist
-	if !userExists {
-		log.Printf("Creating user: %s", config.User)
-		createUserSQL := fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", 
-			quoteIdentifier(config.User), 
-			escapeString(config.Password))
-		
-		if _, err := db.ExecContext(ctx, createUserSQL); err != nil {
-			return fmt.Errorf("failed to create user: %w", err)
-		}
-		log.Printf("User %s created successfully", config.User)
-	} else {
-		log.Printf("User %s already exists", config.User)
-		// Update password if user exists
-		updatePasswordSQL := fmt.Sprintf("ALTER USER %s WITH PASSWORD '%s'", 
-			quoteIdentifier(config.User), 
-			escapeString(config.Password))
-		
-		if _, err := db.ExecContext(ctx, updatePasswordSQL); err != nil {
-			return fmt.Errorf("failed to update user password: %w", err)
-		}
-		log.Printf("Password updated for user %s", config.User)
-	}
+	plan := buildPlan(serverName, server.Databases, actualDatabases, databaseOwners, actualUsers, server.Prune)
+	logPlan(plan)
 
-	// Check if database exists
-	dbExists, err := checkPostgreSQLDatabaseExists(ctx, db, config.Database)
-	if err != nil {
-		return fmt.Errorf("failed to check database existence: %w", err)
+	if isDryRun() {
+		log.Printf("DRY_RUN enabled - not applying changes for %s", serverName)
+		return nil
 	}
 
-	// Create database if it doesn't exist
-	if !dbExists {
-		log.Printf("Creating database: %s", config.Database)
-		createDbSQL := fmt.Sprintf("CREATE DATABASE %s OWNER %s", 
-			quoteIdentifier(config.Database), 
-			quoteIdentifier(config.User))
-		
-		if _, err := db.ExecContext(ctx, createDbSQL); err != nil {
-			return fmt.Errorf("failed to create database: %w", err)
+	// Process each database configuration for this server. A failure on
+	// one database doesn't abort the others, but is collected so the
+	// server-level result passed to observeReconcile - and therefore
+	// reconcile_total and /readyz - reflects it instead of reporting
+	// success whenever at least one database made it through.
+	var failures int
+	for i, dbConfig := range server.Databases {
+		log.Printf("Processing database %d/%d on %s: %s", i+1, len(server.Databases), serverName, dbConfig.Database)
+
+		if err := provisioner.EnsureUser(ctx, db, dbConfig); err != nil {
+			log.Printf("Failed to provision database %s on %s: %v", dbConfig.Database, serverName, err)
+			failures++
+			continue
 		}
-		log.Printf("Database %s created successfully", config.Database)
-	} else {
-		log.Printf("Database %s already exists", config.Database)
-		// Update owner if database exists
-		alterOwnerSQL := fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", 
-			quoteIdentifier(config.Database), 
-			quoteIdentifier(config.User))
-		
-		if _, err := db.ExecContext(ctx, alterOwnerSQL); err != nil {
-			return fmt.Errorf("failed to alter database owner: %w", err)
+		if err := provisioner.EnsureDatabase(ctx, db, dbConfig); err != nil {
+			log.Printf("Failed to provision database %s on %s: %v", dbConfig.Database, serverName, err)
+			failures++
+			continue
+		}
+		if err := provisioner.EnsureGrants(ctx, db, connStr, dbConfig); err != nil {
+			log.Printf("Failed to provision database %s on %s: %v", dbConfig.Database, serverName, err)
+			failures++
+			continue
 		}
-		log.Printf("Owner of database %s set to %s", config.Database, config.User)
-	}
-
-	// Grant all privileges
-	grantSQL := fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s", 
-		quoteIdentifier(config.Database), 
-		quoteIdentifier(config.User))
-	
-	if _, err := db.ExecContext(ctx, grantSQL); err != nil {
-		return fmt.Errorf("failed to grant privileges: %w", err)
-	}
-
-	return nil
-}
-
-func provisionMariaDB(db *sql.DB, config DatabaseConfig) error {
-	ctx := context.Background()
-
-	// Create database if it doesn't exist
-	log.Printf("Creating database if not exists: %s", config.Database)
-	createDbSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", 
-		config.Database)
-	
-	if _, err := db.ExecContext(ctx, createDbSQL); err != nil {
-		return fmt.Errorf("failed to create database: %w", err)
-	}
-
-	// Check if user exists
-	userExists, err := checkMariaDBUserExists(ctx, db, config.User)
-	if err != nil {
-		return fmt.Errorf("failed to check user existence: %w", err)
-	}
 
-	if !userExists {
-		// Create user
-		log.Printf("Creating user: %s", config.User)
-		createUserSQL := fmt.Sprintf("CREATE USER '%s'@'%%' IDENTIFIED BY '%s'", 
-			escapeString(config.User),
-			escapeString(config.Password))
-		
-		if _, err := db.ExecContext(ctx, createUserSQL); err != nil {
-			return fmt.Errorf("failed to create user: %w", err)
+		if planAction(plan, "user", dbConfig.User) == ActionCreate {
+			usersCreatedTotal.Inc()
 		}
-		log.Printf("User %s created successfully", config.User)
-	} else {
-		log.Printf("User %s already exists, updating password", config.User)
-		// Update password
-		updatePasswordSQL := fmt.Sprintf("ALTER USER '%s'@'%%' IDENTIFIED BY '%s'", 
-			escapeString(config.User),
-			escapeString(config.Password))
-		
-		if _, err := db.ExecContext(ctx, updatePasswordSQL); err != nil {
-			return fmt.Errorf("failed to update user password: %w", err)
+		if planAction(plan, "database", dbConfig.Database) == ActionCreate {
+			databasesCreatedTotal.Inc()
 		}
-		log.Printf("Password updated for user %s", config.User)
-	}
 
-	// Grant all privileges on the database to the user
-	grantSQL := fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO '%s'@'%%'", 
-		config.Database,
-		escapeString(config.User))
-	
-	if _, err := db.ExecContext(ctx, grantSQL); err != nil {
-		return fmt.Errorf("failed to grant privileges: %w", err)
+		log.Printf("Successfully provisioned database: %s with user: %s on %s", dbConfig.Database, dbConfig.User, serverName)
 	}
 
-	// Flush privileges
-	if _, err := db.ExecContext(ctx, "FLUSH PRIVILEGES"); err != nil {
-		return fmt.Errorf("failed to flush privileges: %w", err)
+	if err := applyDeletes(ctx, provisioner, db, serverName, plan); err != nil {
+		log.Printf("Failed to prune resources on %s: %v", serverName, err)
+		failures++
 	}
 
-	log.Printf("Granted all privileges on database %s to user %s", config.Database, config.User)
+	if failures > 0 {
+		return fmt.Errorf("%d of %d databases failed to provision on %s", failures, len(server.Databases), serverName)
+	}
 
 	return nil
 }
 
-func checkPostgreSQLUserExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)"
-	err := db.QueryRowContext(ctx, query, username).Scan(&exists)
-	return exists, err
-}
-
-func checkPostgreSQLDatabaseExists(ctx context.Context, db *sql.DB, database string) (bool, error) {
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)"
-	err := db.QueryRowContext(ctx, query, database).Scan(&exists)
-	return exists, err
-}
+func connectWithRetry(driverName, connStr string, maxRetries int, delay time.Duration) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
 
-func checkMariaDBUserExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
-	var count int
-	query := "SELECT COUNT(*) FROM mysql.user WHERE user = ? AND host = '%'"
-	err := db.QueryRowContext(ctx, query, username).Scan(&count)
-	return count > 0, err
-}
+	for i := 0; i < maxRetries; i++ {
+		db, err = sql.Open(driverName, connStr)
+		if err != nil {
+			log.Printf("Attempt %d/%d: Failed to open connection: %v", i+1, maxRetries, err)
+			time.Sleep(delay)
+			continue
+		}
 
-func quoteIdentifier(s string) string {
-	return fmt.Sprintf(`"%s"`, s)
-}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = db.PingContext(ctx)
+		cancel()
 
-func escapeString(s string) string {
-	// Basic SQL string escaping - replace single quotes with two single quotes
-	result := ""
-	for _, c := range s {
-		if c == '\'' {
-			result += "''"
-		} else {
-			result += string(c)
+		if err == nil {
+			return db, nil
 		}
+
+		log.Printf("Attempt %d/%d: Failed to ping database: %v", i+1, maxRetries, err)
+		db.Close()
+		time.Sleep(delay)
 	}
-	return result
+
+	return nil, fmt.Errorf("failed to connect after %d attempts: %w", maxRetries, err)
 }
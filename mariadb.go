@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	registerProvisioner(MariaDB, mariaDBProvisioner{}, "mariadb://", "mysql://")
+}
+
+type mariaDBProvisioner struct{}
+
+func (mariaDBProvisioner) DriverName() string { return "mysql" }
+
+func (mariaDBProvisioner) NormalizeConnectionString(connStr string) string {
+	// The go-sql-driver/mysql driver only recognizes mysql:// DSNs.
+	return strings.Replace(connStr, "mariadb://", "mysql://", 1)
+}
+
+func (mariaDBProvisioner) EnsureUser(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	if err := validateIdentifier(config.User); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	quotedUser := quoteMariaDBLiteral(config.User)
+	quotedPassword := quoteMariaDBLiteral(config.Password)
+
+	userExists, err := checkMariaDBUserExists(ctx, db, config.User)
+	if err != nil {
+		return fmt.Errorf("failed to check user existence: %w", err)
+	}
+
+	if !userExists {
+		log.Printf("Creating user: %s", config.User)
+		createUserSQL := fmt.Sprintf("CREATE USER %s@'%%' IDENTIFIED BY %s", quotedUser, quotedPassword)
+
+		if _, err := db.ExecContext(ctx, createUserSQL); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		log.Printf("User %s created successfully", config.User)
+	} else {
+		log.Printf("User %s already exists, updating password", config.User)
+		updatePasswordSQL := fmt.Sprintf("ALTER USER %s@'%%' IDENTIFIED BY %s", quotedUser, quotedPassword)
+
+		if _, err := db.ExecContext(ctx, updatePasswordSQL); err != nil {
+			return fmt.Errorf("failed to update user password: %w", err)
+		}
+		log.Printf("Password updated for user %s", config.User)
+	}
+
+	return nil
+}
+
+func (mariaDBProvisioner) EnsureDatabase(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	quotedDatabase, err := quoteMariaDBIdentifier(config.Database)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+
+	log.Printf("Creating database if not exists: %s", config.Database)
+	createDbSQL := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci", quotedDatabase)
+
+	if _, err := db.ExecContext(ctx, createDbSQL); err != nil {
+		return fmt.Errorf("failed to create database: %w", err)
+	}
+
+	return nil
+}
+
+func (mariaDBProvisioner) EnsureGrants(ctx context.Context, db *sql.DB, rootConnStr string, config DatabaseConfig) error {
+	quotedDatabase, err := quoteMariaDBIdentifier(config.Database)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+	if err := validateIdentifier(config.User); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	quotedUser := quoteMariaDBLiteral(config.User)
+
+	grantSQL := fmt.Sprintf("GRANT ALL PRIVILEGES ON %s.* TO %s@'%%'", quotedDatabase, quotedUser)
+
+	if _, err := db.ExecContext(ctx, grantSQL); err != nil {
+		return fmt.Errorf("failed to grant privileges: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "FLUSH PRIVILEGES"); err != nil {
+		return fmt.Errorf("failed to flush privileges: %w", err)
+	}
+
+	log.Printf("Granted all privileges on database %s to user %s", config.Database, config.User)
+
+	return nil
+}
+
+func (mariaDBProvisioner) DropDatabase(ctx context.Context, db *sql.DB, name string) error {
+	quoted, err := quoteMariaDBIdentifier(name)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoted))
+	return err
+}
+
+func (mariaDBProvisioner) DropUser(ctx context.Context, db *sql.DB, name string) error {
+	if err := validateIdentifier(name); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DROP USER IF EXISTS %s@'%%'", quoteMariaDBLiteral(name)))
+	return err
+}
+
+var mariaDBSystemDatabases = map[string]bool{"information_schema": true, "mysql": true, "performance_schema": true, "sys": true}
+var mariaDBSystemUsers = map[string]bool{"root": true}
+
+// ListState returns a nil databaseOwners map: MariaDB's GRANT model has no
+// per-database ownership concept, so there's no owner state to diff.
+func (mariaDBProvisioner) ListState(ctx context.Context, db *sql.DB) (databases []string, databaseOwners map[string]string, users []string, err error) {
+	dbRows, err := db.QueryContext(ctx, "SELECT schema_name FROM information_schema.schemata")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("listing databases: %w", err)
+	}
+	defer dbRows.Close()
+
+	for dbRows.Next() {
+		var name string
+		if err := dbRows.Scan(&name); err != nil {
+			return nil, nil, nil, err
+		}
+		if !mariaDBSystemDatabases[name] {
+			databases = append(databases, name)
+		}
+	}
+	if err := dbRows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	userRows, err := db.QueryContext(ctx, "SELECT user FROM mysql.user WHERE host = '%'")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("listing users: %w", err)
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var name string
+		if err := userRows.Scan(&name); err != nil {
+			return nil, nil, nil, err
+		}
+		if !mariaDBSystemUsers[name] {
+			users = append(users, name)
+		}
+	}
+	return databases, nil, users, userRows.Err()
+}
+
+func checkMariaDBUserExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM mysql.user WHERE user = ? AND host = '%'"
+	err := db.QueryRowContext(ctx, query, username).Scan(&count)
+	return count > 0, err
+}
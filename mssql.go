@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	_ "github.com/denisenkom/go-mssqldb"
+)
+
+func init() {
+	registerProvisioner(MSSQL, mssqlProvisioner{}, "sqlserver://", "mssql://")
+}
+
+type mssqlProvisioner struct{}
+
+func (mssqlProvisioner) DriverName() string { return "sqlserver" }
+
+func (mssqlProvisioner) NormalizeConnectionString(connStr string) string {
+	return strings.Replace(connStr, "mssql://", "sqlserver://", 1)
+}
+
+func (mssqlProvisioner) EnsureUser(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	loginExists, err := checkMSSQLLoginExists(ctx, db, config.User)
+	if err != nil {
+		return fmt.Errorf("failed to check login existence: %w", err)
+	}
+
+	if !loginExists {
+		log.Printf("Creating login: %s", config.User)
+		createLoginSQL := fmt.Sprintf("CREATE LOGIN %s WITH PASSWORD = %s",
+			quoteMSSQLIdentifier(config.User),
+			quoteMSSQLLiteral(config.Password))
+
+		if _, err := db.ExecContext(ctx, createLoginSQL); err != nil {
+			return fmt.Errorf("failed to create login: %w", err)
+		}
+		log.Printf("Login %s created successfully", config.User)
+	} else {
+		log.Printf("Login %s already exists", config.User)
+		alterLoginSQL := fmt.Sprintf("ALTER LOGIN %s WITH PASSWORD = %s",
+			quoteMSSQLIdentifier(config.User),
+			quoteMSSQLLiteral(config.Password))
+
+		if _, err := db.ExecContext(ctx, alterLoginSQL); err != nil {
+			return fmt.Errorf("failed to update login password: %w", err)
+		}
+		log.Printf("Password updated for login %s", config.User)
+	}
+
+	return nil
+}
+
+func (mssqlProvisioner) EnsureDatabase(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	dbExists, err := checkMSSQLDatabaseExists(ctx, db, config.Database)
+	if err != nil {
+		return fmt.Errorf("failed to check database existence: %w", err)
+	}
+
+	if !dbExists {
+		log.Printf("Creating database: %s", config.Database)
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s", quoteMSSQLIdentifier(config.Database))); err != nil {
+			return fmt.Errorf("failed to create database: %w", err)
+		}
+		log.Printf("Database %s created successfully", config.Database)
+	} else {
+		log.Printf("Database %s already exists", config.Database)
+	}
+
+	return nil
+}
+
+// EnsureGrants maps the server-level login into the target database and
+// grants it db_owner. This has to run after EnsureDatabase, since a
+// database-scoped user can't be created until the database exists - unlike
+// Postgres/MariaDB, SQL Server users are scoped per-database rather than
+// server-wide.
+//
+// It opens a dedicated connection to the target database, derived from
+// rootConnStr, rather than issuing "USE <db>" on db: database/sql hands each
+// ExecContext call on a pooled *sql.DB an arbitrary connection from the
+// pool, so a "USE" on one call has no guaranteed effect on the next - the
+// CREATE USER/ALTER ROLE statements could silently run against whatever
+// database the connection they land on last had selected.
+func (mssqlProvisioner) EnsureGrants(ctx context.Context, db *sql.DB, rootConnStr string, config DatabaseConfig) error {
+	targetConnStr, err := withMSSQLDatabase(rootConnStr, config.Database)
+	if err != nil {
+		return fmt.Errorf("deriving connection string for database %s: %w", config.Database, err)
+	}
+	targetDB, err := sql.Open("sqlserver", targetConnStr)
+	if err != nil {
+		return fmt.Errorf("failed to open connection to database %s: %w", config.Database, err)
+	}
+	defer targetDB.Close()
+
+	userExists, err := checkMSSQLDatabaseUserExists(ctx, targetDB, config.User)
+	if err != nil {
+		return fmt.Errorf("failed to check database user existence: %w", err)
+	}
+
+	if !userExists {
+		log.Printf("Creating database user: %s", config.User)
+		createUserSQL := fmt.Sprintf("CREATE USER %s FOR LOGIN %s", quoteMSSQLIdentifier(config.User), quoteMSSQLIdentifier(config.User))
+		if _, err := targetDB.ExecContext(ctx, createUserSQL); err != nil {
+			return fmt.Errorf("failed to create database user: %w", err)
+		}
+	}
+
+	grantSQL := fmt.Sprintf("ALTER ROLE db_owner ADD MEMBER %s", quoteMSSQLIdentifier(config.User))
+	if _, err := targetDB.ExecContext(ctx, grantSQL); err != nil {
+		return fmt.Errorf("failed to grant privileges: %w", err)
+	}
+	log.Printf("Granted db_owner on %s to %s", config.Database, config.User)
+
+	return nil
+}
+
+// withMSSQLDatabase sets the "database" query parameter on a sqlserver://
+// connection string so a connection opened from it lands directly on
+// database, instead of the server's default database.
+func withMSSQLDatabase(connStr, database string) (string, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return "", fmt.Errorf("parsing connection string: %w", err)
+	}
+	q := u.Query()
+	q.Set("database", database)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (mssqlProvisioner) DropDatabase(ctx context.Context, db *sql.DB, name string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoteMSSQLIdentifier(name)))
+	return err
+}
+
+func (mssqlProvisioner) DropUser(ctx context.Context, db *sql.DB, name string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DROP LOGIN IF EXISTS %s", quoteMSSQLIdentifier(name)))
+	return err
+}
+
+var mssqlSystemDatabases = map[string]bool{"master": true, "tempdb": true, "model": true, "msdb": true}
+var mssqlSystemLogins = map[string]bool{"sa": true}
+
+// ListState returns a nil databaseOwners map: EnsureGrants adds the login
+// to the db_owner role rather than assigning database ownership itself, so
+// there's no "OWNER TO"-equivalent state to diff here.
+func (mssqlProvisioner) ListState(ctx context.Context, db *sql.DB) (databases []string, databaseOwners map[string]string, users []string, err error) {
+	dbRows, err := db.QueryContext(ctx, "SELECT name FROM sys.databases")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("listing databases: %w", err)
+	}
+	defer dbRows.Close()
+
+	for dbRows.Next() {
+		var name string
+		if err := dbRows.Scan(&name); err != nil {
+			return nil, nil, nil, err
+		}
+		if !mssqlSystemDatabases[name] {
+			databases = append(databases, name)
+		}
+	}
+	if err := dbRows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	loginRows, err := db.QueryContext(ctx, "SELECT name FROM sys.server_principals WHERE type_desc = 'SQL_LOGIN'")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("listing logins: %w", err)
+	}
+	defer loginRows.Close()
+
+	for loginRows.Next() {
+		var name string
+		if err := loginRows.Scan(&name); err != nil {
+			return nil, nil, nil, err
+		}
+		if !mssqlSystemLogins[name] {
+			users = append(users, name)
+		}
+	}
+	return databases, nil, users, loginRows.Err()
+}
+
+func checkMSSQLLoginExists(ctx context.Context, db *sql.DB, login string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM sys.server_principals WHERE name = @p1 AND type_desc = 'SQL_LOGIN'"
+	err := db.QueryRowContext(ctx, query, login).Scan(&count)
+	return count > 0, err
+}
+
+func checkMSSQLDatabaseExists(ctx context.Context, db *sql.DB, database string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM sys.databases WHERE name = @p1"
+	err := db.QueryRowContext(ctx, query, database).Scan(&count)
+	return count > 0, err
+}
+
+func checkMSSQLDatabaseUserExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
+	var count int
+	query := "SELECT COUNT(*) FROM sys.database_principals WHERE name = @p1"
+	err := db.QueryRowContext(ctx, query, username).Scan(&count)
+	return count > 0, err
+}
+
+func quoteMSSQLIdentifier(s string) string {
+	return fmt.Sprintf("[%s]", strings.ReplaceAll(s, "]", "]]"))
+}
+
+func quoteMSSQLLiteral(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+func init() {
+	registerProvisioner(PostgreSQL, postgresProvisioner{}, "postgres://", "postgresql://")
+}
+
+type postgresProvisioner struct{}
+
+func (postgresProvisioner) DriverName() string { return "postgres" }
+
+func (postgresProvisioner) NormalizeConnectionString(connStr string) string {
+	return connStr
+}
+
+func (postgresProvisioner) EnsureUser(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	quotedUser, err := quotePostgresIdentifier(config.User)
+	if err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	quotedPassword := quotePostgresLiteral(config.Password)
+
+	userExists, err := checkPostgreSQLUserExists(ctx, db, config.User)
+	if err != nil {
+		return fmt.Errorf("failed to check user existence: %w", err)
+	}
+
+	if !userExists {
+		log.Printf("Creating user: %s", config.User)
+		createUserSQL := fmt.Sprintf("CREATE USER %s WITH PASSWORD %s", quotedUser, quotedPassword)
+
+		if _, err := db.ExecContext(ctx, createUserSQL); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		log.Printf("User %s created successfully", config.User)
+	} else {
+		log.Printf("User %s already exists", config.User)
+		updatePasswordSQL := fmt.Sprintf("ALTER USER %s WITH PASSWORD %s", quotedUser, quotedPassword)
+
+		if _, err := db.ExecContext(ctx, updatePasswordSQL); err != nil {
+			return fmt.Errorf("failed to update user password: %w", err)
+		}
+		log.Printf("Password updated for user %s", config.User)
+	}
+
+	return nil
+}
+
+func (postgresProvisioner) EnsureDatabase(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	quotedDatabase, err := quotePostgresIdentifier(config.Database)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+	quotedUser, err := quotePostgresIdentifier(config.User)
+	if err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+
+	dbExists, err := checkPostgreSQLDatabaseExists(ctx, db, config.Database)
+	if err != nil {
+		return fmt.Errorf("failed to check database existence: %w", err)
+	}
+
+	if !dbExists {
+		log.Printf("Creating database: %s", config.Database)
+		createDbSQL := fmt.Sprintf("CREATE DATABASE %s OWNER %s", quotedDatabase, quotedUser)
+
+		if _, err := db.ExecContext(ctx, createDbSQL); err != nil {
+			return fmt.Errorf("failed to create database: %w", err)
+		}
+		log.Printf("Database %s created successfully", config.Database)
+	} else {
+		log.Printf("Database %s already exists", config.Database)
+		alterOwnerSQL := fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", quotedDatabase, quotedUser)
+
+		if _, err := db.ExecContext(ctx, alterOwnerSQL); err != nil {
+			return fmt.Errorf("failed to alter database owner: %w", err)
+		}
+		log.Printf("Owner of database %s set to %s", config.Database, config.User)
+	}
+
+	return nil
+}
+
+func (postgresProvisioner) EnsureGrants(ctx context.Context, db *sql.DB, rootConnStr string, config DatabaseConfig) error {
+	quotedDatabase, err := quotePostgresIdentifier(config.Database)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+	quotedUser, err := quotePostgresIdentifier(config.User)
+	if err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+
+	grantSQL := fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s", quotedDatabase, quotedUser)
+
+	if _, err := db.ExecContext(ctx, grantSQL); err != nil {
+		return fmt.Errorf("failed to grant privileges: %w", err)
+	}
+
+	if len(config.Extensions) == 0 && len(config.Schemas) == 0 && len(config.Privileges) == 0 {
+		return nil
+	}
+
+	// Extensions, schemas, and per-role grants are objects inside the
+	// target database's own catalog, not the server-wide one the root
+	// connection sees, so they require a second connection to that
+	// database rather than the root db passed in.
+	targetConnStr, err := withDatabase(rootConnStr, config.Database)
+	if err != nil {
+		return fmt.Errorf("deriving connection string for database %s: %w", config.Database, err)
+	}
+	targetDB, err := sql.Open("postgres", targetConnStr)
+	if err != nil {
+		return fmt.Errorf("failed to open connection to database %s: %w", config.Database, err)
+	}
+	defer targetDB.Close()
+
+	if err := ensurePostgresExtensions(ctx, targetDB, config.Extensions); err != nil {
+		return err
+	}
+	if err := ensurePostgresSchemas(ctx, targetDB, config.Schemas, config.User); err != nil {
+		return err
+	}
+	if err := ensurePostgresPrivileges(ctx, db, targetDB, config.Database, config.Privileges); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// withDatabase rewrites connStr so it points at database instead of
+// whatever database the root connection used. detectDBType defaults
+// unprefixed root connection strings to Postgres, so connStr here may be
+// either a URL ("postgres://...") or a libpq keyword/value DSN
+// ("host=... dbname=..."); both forms are handled.
+func withDatabase(connStr, database string) (string, error) {
+	if strings.Contains(connStr, "://") {
+		u, err := url.Parse(connStr)
+		if err != nil {
+			return "", fmt.Errorf("parsing connection string: %w", err)
+		}
+		u.Path = "/" + database
+		return u.String(), nil
+	}
+	return withKeywordDatabase(connStr, database), nil
+}
+
+// dbnameKeywordPattern matches a "dbname=value" keyword in a libpq
+// keyword/value connection string, where value is either unquoted (up to
+// the next whitespace) or single-quoted with backslash escapes.
+var dbnameKeywordPattern = regexp.MustCompile(`dbname=(?:'(?:\\.|[^'\\])*'|\S*)`)
+
+// withKeywordDatabase replaces (or appends) the dbname keyword in a libpq
+// keyword/value connection string. database is assumed already validated
+// by the caller via quotePostgresIdentifier, so it can't contain quotes,
+// whitespace, or backslashes that would need escaping here.
+func withKeywordDatabase(connStr, database string) string {
+	replacement := "dbname=" + database
+	if dbnameKeywordPattern.MatchString(connStr) {
+		return dbnameKeywordPattern.ReplaceAllString(connStr, replacement)
+	}
+	if connStr == "" {
+		return replacement
+	}
+	return connStr + " " + replacement
+}
+
+func ensurePostgresExtensions(ctx context.Context, db *sql.DB, extensions []string) error {
+	for _, ext := range extensions {
+		quotedExt, err := quotePostgresExtensionName(ext)
+		if err != nil {
+			return fmt.Errorf("invalid extension %q: %w", ext, err)
+		}
+		log.Printf("Ensuring extension %s", ext)
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", quotedExt)); err != nil {
+			return fmt.Errorf("failed to create extension %s: %w", ext, err)
+		}
+	}
+	return nil
+}
+
+func ensurePostgresSchemas(ctx context.Context, db *sql.DB, schemas []string, owner string) error {
+	quotedOwner, err := quotePostgresIdentifier(owner)
+	if err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	for _, schema := range schemas {
+		quotedSchema, err := quotePostgresIdentifier(schema)
+		if err != nil {
+			return fmt.Errorf("invalid schema %q: %w", schema, err)
+		}
+		log.Printf("Ensuring schema %s owned by %s", schema, owner)
+		createSQL := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s AUTHORIZATION %s", quotedSchema, quotedOwner)
+		if _, err := db.ExecContext(ctx, createSQL); err != nil {
+			return fmt.Errorf("failed to create schema %s: %w", schema, err)
+		}
+	}
+	return nil
+}
+
+// postgresTablePrivileges are the privilege keywords valid on "ALL TABLES IN
+// SCHEMA" and "ALTER DEFAULT PRIVILEGES ... ON TABLES" grants. CONNECT and
+// USAGE are handled separately since they target the database and schema
+// themselves rather than the tables within it.
+var postgresTablePrivileges = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	"TRUNCATE": true, "REFERENCES": true, "TRIGGER": true,
+}
+
+// ensurePostgresRoleExists creates role as a plain login role if it doesn't
+// already exist. Privileges[].Role isn't necessarily one of the Databases[]
+// entries' User - e.g. a read-only reporting role managed only through
+// Privileges - so EnsureGrants can't rely on EnsureUser having created it.
+func ensurePostgresRoleExists(ctx context.Context, db *sql.DB, role string) error {
+	exists, err := checkPostgreSQLUserExists(ctx, db, role)
+	if err != nil {
+		return fmt.Errorf("checking role %s: %w", role, err)
+	}
+	if exists {
+		return nil
+	}
+
+	quotedRole, err := quotePostgresIdentifier(role)
+	if err != nil {
+		return fmt.Errorf("invalid role %q: %w", role, err)
+	}
+	log.Printf("Creating role: %s", role)
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE ROLE %s WITH LOGIN", quotedRole)); err != nil {
+		return fmt.Errorf("failed to create role %s: %w", role, err)
+	}
+	return nil
+}
+
+// ensurePostgresPrivileges applies each RolePrivilege's grants. CONNECT is a
+// database-level grant so it runs on rootDB; USAGE and table privileges are
+// schema-level and run on targetDB, the connection to the database itself.
+func ensurePostgresPrivileges(ctx context.Context, rootDB, targetDB *sql.DB, database string, privileges []RolePrivilege) error {
+	quotedDatabase, err := quotePostgresIdentifier(database)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+
+	for _, priv := range privileges {
+		quotedRole, err := quotePostgresIdentifier(priv.Role)
+		if err != nil {
+			return fmt.Errorf("invalid role %q: %w", priv.Role, err)
+		}
+		if err := ensurePostgresRoleExists(ctx, rootDB, priv.Role); err != nil {
+			return err
+		}
+
+		var quotedSchema string
+		if priv.Schema != "" {
+			quotedSchema, err = quotePostgresIdentifier(priv.Schema)
+			if err != nil {
+				return fmt.Errorf("invalid schema %q: %w", priv.Schema, err)
+			}
+		}
+
+		// Validate every keyword - and that a schema is present if any
+		// keyword needs one - before executing anything, so a malformed
+		// entry fails atomically instead of partially applying.
+		var wantsConnect, wantsUsage bool
+		var tablePrivileges []string
+		for _, p := range priv.Privileges {
+			switch keyword := strings.ToUpper(p); keyword {
+			case "CONNECT":
+				wantsConnect = true
+			case "USAGE":
+				wantsUsage = true
+			default:
+				if !postgresTablePrivileges[keyword] {
+					return fmt.Errorf("role %s: unsupported privilege %q", priv.Role, p)
+				}
+				tablePrivileges = append(tablePrivileges, keyword)
+			}
+		}
+		if (wantsUsage || len(tablePrivileges) > 0) && quotedSchema == "" {
+			return fmt.Errorf("role %s: USAGE and table privileges require a schema", priv.Role)
+		}
+
+		if wantsConnect {
+			if _, err := rootDB.ExecContext(ctx, fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", quotedDatabase, quotedRole)); err != nil {
+				return fmt.Errorf("failed to grant CONNECT to %s: %w", priv.Role, err)
+			}
+		}
+		if wantsUsage {
+			if _, err := targetDB.ExecContext(ctx, fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", quotedSchema, quotedRole)); err != nil {
+				return fmt.Errorf("failed to grant USAGE to %s: %w", priv.Role, err)
+			}
+		}
+
+		if len(tablePrivileges) == 0 {
+			continue
+		}
+		privList := strings.Join(tablePrivileges, ", ")
+
+		grantSQL := fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA %s TO %s", privList, quotedSchema, quotedRole)
+		if _, err := targetDB.ExecContext(ctx, grantSQL); err != nil {
+			return fmt.Errorf("failed to grant %s on schema %s to %s: %w", privList, priv.Schema, priv.Role, err)
+		}
+
+		defaultSQL := fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT %s ON TABLES TO %s", quotedSchema, privList, quotedRole)
+		if _, err := targetDB.ExecContext(ctx, defaultSQL); err != nil {
+			return fmt.Errorf("failed to set default privileges on schema %s for %s: %w", priv.Schema, priv.Role, err)
+		}
+
+		log.Printf("Granted %s on schema %s to %s", privList, priv.Schema, priv.Role)
+	}
+
+	return nil
+}
+
+func (postgresProvisioner) DropDatabase(ctx context.Context, db *sql.DB, name string) error {
+	quoted, err := quotePostgresIdentifier(name)
+	if err != nil {
+		return fmt.Errorf("invalid database: %w", err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoted))
+	return err
+}
+
+func (postgresProvisioner) DropUser(ctx context.Context, db *sql.DB, name string) error {
+	quoted, err := quotePostgresIdentifier(name)
+	if err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP ROLE IF EXISTS %s", quoted))
+	return err
+}
+
+var postgresSystemDatabases = map[string]bool{"template0": true, "template1": true, "postgres": true}
+var postgresSystemUsers = map[string]bool{"postgres": true}
+
+func (postgresProvisioner) ListState(ctx context.Context, db *sql.DB) (databases []string, databaseOwners map[string]string, users []string, err error) {
+	dbRows, err := db.QueryContext(ctx, "SELECT datname, pg_catalog.pg_get_userbyid(datdba) FROM pg_database WHERE datistemplate = false")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("listing databases: %w", err)
+	}
+	defer dbRows.Close()
+
+	databaseOwners = map[string]string{}
+	for dbRows.Next() {
+		var name, owner string
+		if err := dbRows.Scan(&name, &owner); err != nil {
+			return nil, nil, nil, err
+		}
+		if !postgresSystemDatabases[name] {
+			databases = append(databases, name)
+			databaseOwners[name] = owner
+		}
+	}
+	if err := dbRows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	userRows, err := db.QueryContext(ctx, `SELECT rolname FROM pg_roles WHERE rolcanlogin = true AND rolname NOT LIKE 'pg\_%' ESCAPE '\'`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("listing users: %w", err)
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var name string
+		if err := userRows.Scan(&name); err != nil {
+			return nil, nil, nil, err
+		}
+		if !postgresSystemUsers[name] {
+			users = append(users, name)
+		}
+	}
+	return databases, databaseOwners, users, userRows.Err()
+}
+
+func checkPostgreSQLUserExists(ctx context.Context, db *sql.DB, username string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)"
+	err := db.QueryRowContext(ctx, query, username).Scan(&exists)
+	return exists, err
+}
+
+func checkPostgreSQLDatabaseExists(ctx context.Context, db *sql.DB, database string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS(SELECT 1 FROM pg_database WHERE datname = $1)"
+	err := db.QueryRowContext(ctx, query, database).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DBType identifies which backend provisions a given server. It's a
+// registry key rather than a fixed enum so new backends can add themselves
+// (via registerProvisioner) without editing this file.
+type DBType string
+
+const (
+	PostgreSQL DBType = "postgresql"
+	MariaDB    DBType = "mariadb"
+	SQLite     DBType = "sqlite"
+	MSSQL      DBType = "mssql"
+)
+
+// Provisioner is the common interface every supported database backend
+// implements. processConfig drives it generically: EnsureUser runs first
+// (server-level principals, e.g. Postgres roles or SQL Server logins),
+// then EnsureDatabase, then EnsureGrants (which for backends where users
+// are scoped to a database, like SQL Server, is where the database-level
+// user and role membership are actually created).
+type Provisioner interface {
+	// DriverName is the database/sql driver used to open the root connection.
+	DriverName() string
+	// NormalizeConnectionString adapts the server's configured
+	// root_connection_string into a DSN the driver accepts.
+	NormalizeConnectionString(connStr string) string
+	// ListState returns the databases and users that currently exist on
+	// the server, excluding built-in system ones, for reconcile diffing.
+	// databaseOwners maps each returned database name to its current
+	// owning role, for backends whose EnsureDatabase actually assigns
+	// database ownership (today, only Postgres's "OWNER TO"). Backends
+	// without that concept - MariaDB's GRANT-only model, MSSQL's db_owner
+	// role membership, SQLite's plain files - return a nil map, and
+	// buildPlan treats that as "ownership state unknown" rather than
+	// claiming every existing database is out of sync.
+	ListState(ctx context.Context, db *sql.DB) (databases []string, databaseOwners map[string]string, users []string, err error)
+	EnsureUser(ctx context.Context, db *sql.DB, config DatabaseConfig) error
+	EnsureDatabase(ctx context.Context, db *sql.DB, config DatabaseConfig) error
+	// EnsureGrants applies ownership and any fine-grained privileges for
+	// config. rootConnStr is the server's normalized root connection string;
+	// backends that need to run statements inside the target database
+	// itself (e.g. Postgres extensions and schema grants) derive a
+	// second connection from it rather than the root db passed in.
+	EnsureGrants(ctx context.Context, db *sql.DB, rootConnStr string, config DatabaseConfig) error
+	DropDatabase(ctx context.Context, db *sql.DB, name string) error
+	DropUser(ctx context.Context, db *sql.DB, name string) error
+}
+
+var (
+	provisioners  = map[DBType]Provisioner{}
+	dbTypeSchemes = map[string]DBType{}
+)
+
+// registerProvisioner wires a Provisioner into the registry and maps the
+// connection-string schemes that select it. Backends call this from an
+// init() function.
+func registerProvisioner(dbType DBType, p Provisioner, schemes ...string) {
+	provisioners[dbType] = p
+	for _, scheme := range schemes {
+		dbTypeSchemes[scheme] = dbType
+	}
+}
+
+// detectDBType inspects the scheme of connStr against the registered
+// backends, defaulting to PostgreSQL when nothing else matches (root
+// connection strings historically didn't need a "postgres://" prefix).
+func detectDBType(connStr string) DBType {
+	for scheme, dbType := range dbTypeSchemes {
+		if strings.HasPrefix(connStr, scheme) {
+			return dbType
+		}
+	}
+	return PostgreSQL
+}
+
+func provisionerFor(dbType DBType) (Provisioner, error) {
+	p, ok := provisioners[dbType]
+	if !ok {
+		return nil, fmt.Errorf("no provisioner registered for database type %q", dbType)
+	}
+	return p, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+)
+
+// Action describes what a reconcile plan will do to a single resource.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionNoop   Action = "noop"
+	ActionDelete Action = "delete"
+)
+
+// PlannedChange is one line of a reconcile plan: what will happen to a
+// single database or user on a single server.
+type PlannedChange struct {
+	Server   string
+	Resource string // "database" or "user"
+	Name     string
+	Action   Action
+}
+
+// isDryRun reports whether DRY_RUN=true is set, in which case processConfig
+// computes and logs a plan without executing any DDL.
+func isDryRun() bool {
+	return strings.EqualFold(os.Getenv("DRY_RUN"), "true")
+}
+
+// buildPlan compares the databases/users declared in desired against what's
+// actually present on the server (from Provisioner.ListState) and returns
+// the changes needed to reconcile actual state to desired state. When
+// prune is false, actual resources that aren't in desired are reported as
+// noop instead of delete. databaseOwners, also from ListState, is used to
+// tell an out-of-sync owner (ActionUpdate) from an already-correct one
+// (ActionNoop); it's nil for backends with no ownership concept to diff, in
+// which case an existing database is always reported noop rather than
+// guessing.
+func buildPlan(serverName string, desired []DatabaseConfig, actualDatabases []string, databaseOwners map[string]string, actualUsers []string, prune bool) []PlannedChange {
+	desiredDatabases := map[string]bool{}
+	desiredUsers := map[string]bool{}
+	for _, d := range desired {
+		desiredDatabases[d.Database] = true
+		desiredUsers[d.User] = true
+		// Roles referenced only under Privileges (e.g. a read-only
+		// reporting role) are still login roles on the server, so they
+		// must count as desired too - otherwise ListState picks them up
+		// as undeclared and prune drops the role EnsureGrants just
+		// configured.
+		for _, p := range d.Privileges {
+			desiredUsers[p.Role] = true
+		}
+	}
+
+	actualDatabaseSet := toSet(actualDatabases)
+	actualUserSet := toSet(actualUsers)
+
+	var plan []PlannedChange
+
+	for _, d := range desired {
+		databaseAction := ActionCreate
+		if actualDatabaseSet[d.Database] {
+			switch {
+			case databaseOwners == nil:
+				databaseAction = ActionNoop
+			case databaseOwners[d.Database] != d.User:
+				databaseAction = ActionUpdate
+			default:
+				databaseAction = ActionNoop
+			}
+		}
+		plan = append(plan, PlannedChange{Server: serverName, Resource: "database", Name: d.Database, Action: databaseAction})
+
+		userAction := ActionUpdate
+		if !actualUserSet[d.User] {
+			userAction = ActionCreate
+		}
+		plan = append(plan, PlannedChange{Server: serverName, Resource: "user", Name: d.User, Action: userAction})
+	}
+
+	for _, name := range actualDatabases {
+		if desiredDatabases[name] {
+			continue
+		}
+		action := ActionNoop
+		if prune {
+			action = ActionDelete
+		}
+		plan = append(plan, PlannedChange{Server: serverName, Resource: "database", Name: name, Action: action})
+	}
+
+	for _, name := range actualUsers {
+		if desiredUsers[name] {
+			continue
+		}
+		action := ActionNoop
+		if prune {
+			action = ActionDelete
+		}
+		plan = append(plan, PlannedChange{Server: serverName, Resource: "user", Name: name, Action: action})
+	}
+
+	return plan
+}
+
+// planAction looks up the action a plan assigned to a specific resource,
+// e.g. to decide whether a successful EnsureUser call was a create or an
+// update for metrics purposes.
+func planAction(plan []PlannedChange, resource, name string) Action {
+	for _, change := range plan {
+		if change.Resource == resource && change.Name == name {
+			return change.Action
+		}
+	}
+	return ActionNoop
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// logPlan prints the reconcile plan before it's applied, so an operator (or
+// a DRY_RUN run) can see exactly what will change.
+func logPlan(plan []PlannedChange) {
+	log.Printf("Reconcile plan (%d changes):", len(plan))
+	for _, change := range plan {
+		log.Printf("  [%s] %s %q on %s", change.Action, change.Resource, change.Name, change.Server)
+	}
+}
+
+// applyDeletes drops the databases and users marked ActionDelete in plan.
+// It's only reached when prune is enabled for the server and DRY_RUN isn't set.
+func applyDeletes(ctx context.Context, provisioner Provisioner, db *sql.DB, serverName string, plan []PlannedChange) error {
+	for _, change := range plan {
+		if change.Action != ActionDelete {
+			continue
+		}
+
+		var err error
+		switch change.Resource {
+		case "database":
+			err = provisioner.DropDatabase(ctx, db, change.Name)
+		case "user":
+			err = provisioner.DropUser(ctx, db, change.Name)
+		}
+
+		if err != nil {
+			log.Printf("Failed to delete %s %q on %s: %v", change.Resource, change.Name, serverName, err)
+			continue
+		}
+		log.Printf("Deleted %s %q on %s", change.Resource, change.Name, serverName)
+	}
+	return nil
+}
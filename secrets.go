@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretResolver resolves an indirect secret reference into its plaintext value.
+// A reference looks like "<scheme>://<path>[#<field>]", e.g.
+// "vault://secret/data/pg#password" or "env://PG_ROOT_DSN".
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretResolver{}
+)
+
+// RegisterSecretProvider registers a SecretResolver for the given scheme
+// (without "://"). Providers are expected to register themselves from an
+// init() function so they're available before loadConfig runs.
+func RegisterSecretProvider(scheme string, resolver SecretResolver) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[scheme] = resolver
+}
+
+func init() {
+	RegisterSecretProvider("env", envSecretResolver{})
+	RegisterSecretProvider("file", fileSecretResolver{})
+	RegisterSecretProvider("vault", withCache(vaultSecretResolver{}))
+	RegisterSecretProvider("aws-sm", withCache(awsSecretsManagerResolver{}))
+}
+
+// ResolveSecret resolves value if it looks like a secret reference
+// ("<scheme>://..."). Values that don't match a registered scheme are
+// returned unchanged, so plain inline passwords keep working.
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+
+	secretProvidersMu.RLock()
+	resolver, ok := secretProviders[scheme]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return value, nil
+	}
+
+	resolved, err := resolver.Resolve(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("resolving secret %q: %w", value, err)
+	}
+	return resolved, nil
+}
+
+// resolveConfigSecrets resolves every Password and RootConnectionString in
+// config in place. It's called from loadConfig before processConfig runs,
+// so the rest of the provisioner never has to know secrets were indirect.
+func resolveConfigSecrets(ctx context.Context, config *Config) error {
+	for i := range config.Servers {
+		server := &config.Servers[i]
+
+		resolved, err := ResolveSecret(ctx, server.RootConnectionString)
+		if err != nil {
+			return fmt.Errorf("server %d (%s): root connection string: %w", i, server.Name, err)
+		}
+		server.RootConnectionString = resolved
+
+		for j := range server.Databases {
+			db := &server.Databases[j]
+			resolved, err := ResolveSecret(ctx, db.Password)
+			if err != nil {
+				return fmt.Errorf("server %d (%s): database %s: password: %w", i, server.Name, db.Database, err)
+			}
+			db.Password = resolved
+		}
+	}
+	return nil
+}
+
+// splitRefPathField splits a "scheme://path#field" reference into its path
+// (without the scheme) and optional field.
+func splitRefPathField(ref string) (path string, field string) {
+	_, rest, _ := strings.Cut(ref, "://")
+	path, field, _ = strings.Cut(rest, "#")
+	return path, field
+}
+
+// envSecretResolver resolves "env://NAME" from the process environment.
+type envSecretResolver struct{}
+
+func (envSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	name, _ := splitRefPathField(ref)
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", name)
+	}
+	return value, nil
+}
+
+// fileSecretResolver resolves "file:///path/to/secret" by reading the file
+// (e.g. a Kubernetes/Docker secret mount) and trimming a trailing newline.
+type fileSecretResolver struct{}
+
+func (fileSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	path, _ := splitRefPathField(ref)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file %q: %w", path, err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// vaultSecretResolver resolves "vault://<mount>/<path>#<field>" against a
+// HashiCorp Vault server, using VAULT_ADDR and VAULT_TOKEN for
+// authentication. It understands both KV v2 (nested "data.data") and KV v1
+// ("data") response shapes.
+type vaultSecretResolver struct{}
+
+func (vaultSecretResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	path, field, err := requireField(ref)
+	if err != nil {
+		return "", err
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set to resolve vault:// secrets")
+	}
+
+	url := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("contacting vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s: %s", resp.Status, body)
+	}
+
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("parsing vault response: %w", err)
+	}
+
+	data := payload.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested // KV v2
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found at vault path %q", field, path)
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// awsSecretsManagerResolver resolves "aws-sm://<secret-id>#<field>" via the
+// AWS Secrets Manager GetSecretValue API, using the standard
+// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN/AWS_REGION
+// environment variables and SigV4 request signing.
+type awsSecretsManagerResolver struct{}
+
+func (awsSecretsManagerResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	secretID, field, err := requireField(ref)
+	if err != nil {
+		return "", err
+	}
+
+	region := firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		return "", fmt.Errorf("AWS_REGION (or AWS_DEFAULT_REGION) must be set to resolve aws-sm:// secrets")
+	}
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return "", fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to resolve aws-sm:// secrets")
+	}
+
+	body := []byte(fmt.Sprintf(`{"SecretId":%q}`, secretID))
+	host := fmt.Sprintf("secretsmanager.%s.amazonaws.com", region)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+	signAWSRequestV4(req, body, region, "secretsmanager", accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("contacting secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets manager returned %s: %s", resp.Status, respBody)
+	}
+
+	var payload struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return "", fmt.Errorf("parsing secrets manager response: %w", err)
+	}
+
+	if field == "" {
+		return payload.SecretString, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(payload.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret %q is not a JSON object, cannot extract field %q", secretID, field)
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found in secret %q", field, secretID)
+	}
+	return fmt.Sprintf("%v", value), nil
+}
+
+// signAWSRequestV4 signs req in place using AWS Signature Version 4.
+func signAWSRequestV4(req *http.Request, body []byte, region, service, accessKey, secretKey, sessionToken string) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("Host", req.URL.Host)
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date;x-amz-target"
+	canonicalHeaders := fmt.Sprintf(
+		"content-type:%s\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\nx-amz-target:%s\n",
+		req.Header.Get("Content-Type"), req.URL.Host, payloadHash, amzDate, req.Header.Get("X-Amz-Target"),
+	)
+	if sessionToken != "" {
+		signedHeaders = "content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token;x-amz-target"
+		canonicalHeaders = fmt.Sprintf(
+			"content-type:%s\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\nx-amz-security-token:%s\nx-amz-target:%s\n",
+			req.Header.Get("Content-Type"), req.URL.Host, payloadHash, amzDate, sessionToken, req.Header.Get("X-Amz-Target"),
+		)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, "/", "", canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, credentialScope, sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256(hmacSHA256(hmacSHA256(hmacSHA256([]byte("AWS4"+secretKey), dateStamp), region), service), "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256Raw(signingKey, stringToSign))
+
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, credentialScope, signedHeaders, signature)
+	req.Header.Set("Authorization", authHeader)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	return hmacSHA256Raw(key, data)
+}
+
+func hmacSHA256Raw(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func requireField(ref string) (path, field string, err error) {
+	path, field = splitRefPathField(ref)
+	if field == "" {
+		return "", "", fmt.Errorf("secret reference %q is missing a #field suffix", ref)
+	}
+	return path, field, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// secretCacheTTL controls how long resolved values from external secret
+// backends are cached, so watch mode's reconcile loop doesn't hammer Vault
+// or AWS on every cycle. Override with SECRET_CACHE_TTL (e.g. "30s").
+func secretCacheTTL() time.Duration {
+	if raw := os.Getenv("SECRET_CACHE_TTL"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d
+		}
+	}
+	return 5 * time.Minute
+}
+
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// cachingResolver wraps a SecretResolver with a TTL cache keyed by the raw
+// reference string, so repeated resolutions of the same reference within
+// the TTL window don't make a new external API call.
+type cachingResolver struct {
+	inner SecretResolver
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func withCache(inner SecretResolver) *cachingResolver {
+	return &cachingResolver{inner: inner, entries: map[string]cacheEntry{}}
+}
+
+func (c *cachingResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[ref]; ok && time.Now().Before(entry.expiresAt) {
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	c.mu.Unlock()
+
+	value, err := c.inner.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[ref] = cacheEntry{value: value, expiresAt: time.Now().Add(secretCacheTTL())}
+	c.mu.Unlock()
+
+	return value, nil
+}
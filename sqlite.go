@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	registerProvisioner(SQLite, sqliteProvisioner{}, "sqlite://")
+}
+
+// sqliteProvisioner has no user/database-server concept: each
+// DatabaseConfig.Database is a filesystem path, and "provisioning" it just
+// means the file exists. There's no user/grant model to enforce, so
+// EnsureUser and EnsureGrants are no-ops.
+type sqliteProvisioner struct{}
+
+func (sqliteProvisioner) DriverName() string { return "sqlite" }
+
+func (sqliteProvisioner) NormalizeConnectionString(connStr string) string {
+	// SQLite has no server to connect to; root_connection_string only
+	// selects this backend via its scheme. Open an in-memory root
+	// connection to drive EnsureDatabase's ATTACH/DETACH.
+	return ":memory:"
+}
+
+func (sqliteProvisioner) EnsureUser(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	log.Printf("SQLite has no user model, skipping user provisioning for %s", config.User)
+	return nil
+}
+
+func (sqliteProvisioner) EnsureDatabase(ctx context.Context, db *sql.DB, config DatabaseConfig) error {
+	if dir := filepath.Dir(config.Database); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for sqlite database: %w", err)
+		}
+	}
+
+	log.Printf("Creating sqlite database file if not exists: %s", config.Database)
+
+	// ATTACH creates the target file if it doesn't already exist; DETACH
+	// immediately releases it so it can be opened directly later.
+	attachSQL := fmt.Sprintf("ATTACH DATABASE %s AS provisioned_db", sqliteQuoteLiteral(config.Database))
+	if _, err := db.ExecContext(ctx, attachSQL); err != nil {
+		return fmt.Errorf("failed to create database file: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "DETACH DATABASE provisioned_db"); err != nil {
+		return fmt.Errorf("failed to detach database file: %w", err)
+	}
+
+	log.Printf("SQLite database %s created successfully", config.Database)
+	return nil
+}
+
+func (sqliteProvisioner) EnsureGrants(ctx context.Context, db *sql.DB, rootConnStr string, config DatabaseConfig) error {
+	log.Printf("SQLite has no grant model, skipping grant provisioning for %s", config.User)
+	return nil
+}
+
+func (sqliteProvisioner) DropDatabase(ctx context.Context, db *sql.DB, name string) error {
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove database file: %w", err)
+	}
+	return nil
+}
+
+func (sqliteProvisioner) DropUser(ctx context.Context, db *sql.DB, name string) error {
+	return nil
+}
+
+func (sqliteProvisioner) ListState(ctx context.Context, db *sql.DB) (databases []string, databaseOwners map[string]string, users []string, err error) {
+	// There's no server-wide catalog of sqlite files to diff against, so
+	// reconcile pruning isn't supported for this backend.
+	return nil, nil, nil, nil
+}
+
+func sqliteQuoteLiteral(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// identifierPattern is deliberately strict: plain identifiers only, no
+// quotes, backticks, backslashes, or null bytes that a dialect's quoting
+// rules might mishandle. 63 bytes matches Postgres's NAMEDATALEN limit.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,63}$`)
+
+// validateIdentifier rejects anything that isn't a plain identifier before
+// it's interpolated into DDL. Quoting alone isn't enough - it just changes
+// which metacharacter needs escaping - so every identifier is validated
+// against a strict allowlist first.
+func validateIdentifier(name string) error {
+	if !identifierPattern.MatchString(name) {
+		return fmt.Errorf("invalid identifier %q: must match %s", name, identifierPattern.String())
+	}
+	return nil
+}
+
+// quotePostgresIdentifier validates and double-quotes a Postgres identifier.
+func quotePostgresIdentifier(name string) (string, error) {
+	if err := validateIdentifier(name); err != nil {
+		return "", err
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`, nil
+}
+
+// quotePostgresLiteral safely quotes a string as a Postgres string literal,
+// correctly handling embedded quotes and backslashes.
+func quotePostgresLiteral(s string) string {
+	return pq.QuoteLiteral(s)
+}
+
+// extensionNamePattern is looser than identifierPattern: real extension
+// names like "uuid-ossp" contain hyphens, which are never valid in a user,
+// database, or schema identifier but are fine inside double quotes.
+var extensionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,63}$`)
+
+// quotePostgresExtensionName validates and double-quotes a Postgres
+// extension name for use in CREATE EXTENSION.
+func quotePostgresExtensionName(name string) (string, error) {
+	if !extensionNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid extension name %q: must match %s", name, extensionNamePattern.String())
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`, nil
+}
+
+// quoteMariaDBIdentifier validates and backtick-quotes a MariaDB identifier.
+func quoteMariaDBIdentifier(name string) (string, error) {
+	if err := validateIdentifier(name); err != nil {
+		return "", err
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`", nil
+}
+
+// mariaDBLiteralReplacer escapes both the string-literal terminator and the
+// backslash escape character MariaDB recognizes by default (sql_mode
+// without NO_BACKSLASH_ESCAPES) - escaping only the quote, as the old
+// escapeString helper did, left a literal open to a trailing backslash that
+// escapes the closing quote instead of being taken as a literal character.
+var mariaDBLiteralReplacer = strings.NewReplacer(`\`, `\\`, `'`, `''`)
+
+// quoteMariaDBLiteral safely quotes a string as a MariaDB string literal.
+func quoteMariaDBLiteral(s string) string {
+	return "'" + mariaDBLiteralReplacer.Replace(s) + "'"
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "plain", input: "app_user", wantErr: false},
+		{name: "digits and underscore", input: "db_1", wantErr: false},
+		{name: "empty", input: "", wantErr: true},
+		{name: "embedded double quote", input: `evil"name`, wantErr: true},
+		{name: "embedded single quote", input: "evil'name", wantErr: true},
+		{name: "embedded backtick", input: "evil`name", wantErr: true},
+		{name: "embedded backslash", input: `evil\name`, wantErr: true},
+		{name: "embedded null byte", input: "evil\x00name", wantErr: true},
+		{name: "embedded space", input: "evil name", wantErr: true},
+		{name: "embedded semicolon", input: "name; DROP TABLE x;--", wantErr: true},
+		{name: "hyphen not allowed", input: "evil-name", wantErr: true},
+		{name: "too long", input: strings.Repeat("a", 64), wantErr: true},
+		{name: "max length ok", input: strings.Repeat("a", 63), wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIdentifier(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateIdentifier(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuotePostgresIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", input: "app_user", want: `"app_user"`},
+		{name: "embedded double quote rejected", input: `evil"name`, wantErr: true},
+		{name: "embedded backslash rejected", input: `evil\name`, wantErr: true},
+		{name: "null byte rejected", input: "evil\x00name", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quotePostgresIdentifier(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("quotePostgresIdentifier(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("quotePostgresIdentifier(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotePostgresLiteral(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "embedded single quote", input: "p'assword"},
+		{name: "embedded backslash", input: `p\assword`},
+		{name: "quote then backslash", input: `'\`},
+		{name: "null byte", input: "pass\x00word"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quoted := quotePostgresLiteral(tt.input)
+			// pq.QuoteLiteral emits a plain 'literal', or an escape-string
+			// E'literal' (with a leading space) when the value needs
+			// backslash escaping under non-standard_conforming_strings.
+			trimmed := strings.TrimPrefix(strings.TrimSpace(quoted), "E")
+			if !strings.HasPrefix(trimmed, "'") || !strings.HasSuffix(trimmed, "'") {
+				t.Errorf("quotePostgresLiteral(%q) = %q, want a string literal", tt.input, quoted)
+			}
+			// A correctly escaped literal never ends the string early: it
+			// must be well-formed (odd number of single quotes between the
+			// outer delimiters would mean a premature close).
+			if strings.Count(quoted, "'")%2 != 0 {
+				t.Errorf("quotePostgresLiteral(%q) = %q, has an unbalanced number of quotes", tt.input, quoted)
+			}
+		})
+	}
+}
+
+func TestQuoteMariaDBIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", input: "app_user", want: "`app_user`"},
+		{name: "embedded backtick rejected", input: "evil`name", wantErr: true},
+		{name: "embedded backslash rejected", input: `evil\name`, wantErr: true},
+		{name: "null byte rejected", input: "evil\x00name", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quoteMariaDBIdentifier(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("quoteMariaDBIdentifier(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("quoteMariaDBIdentifier(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteMariaDBLiteral(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "embedded single quote", input: "p'assword", want: `'p''assword'`},
+		// Under MariaDB's default sql_mode (NO_BACKSLASH_ESCAPES off),
+		// backslash is itself the escape character, so a literal backslash
+		// must be doubled or it escapes the closing quote instead of
+		// terminating the string.
+		{name: "embedded backslash", input: `p\assword`, want: `'p\\assword'`},
+		{name: "trailing backslash", input: `secret\`, want: `'secret\\'`},
+		{name: "quote then backslash", input: `'\`, want: `'''\\'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quoteMariaDBLiteral(tt.input)
+			if got != tt.want {
+				t.Errorf("quoteMariaDBLiteral(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotePostgresExtensionName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "hyphenated name allowed", input: "uuid-ossp", want: `"uuid-ossp"`},
+		{name: "underscored name allowed", input: "pg_stat_statements", want: `"pg_stat_statements"`},
+		{name: "embedded double quote rejected", input: `evil"ext`, wantErr: true},
+		{name: "embedded backslash rejected", input: `evil\ext`, wantErr: true},
+		{name: "null byte rejected", input: "evil\x00ext", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quotePostgresExtensionName(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("quotePostgresExtensionName(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("quotePostgresExtensionName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
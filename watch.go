@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce coalesces bursts of filesystem events (e.g. the several
+// rename/create events a Kubernetes ConfigMap update produces) into a
+// single reload.
+const reloadDebounce = 500 * time.Millisecond
+
+// runWatchMode watches the config file's directory for changes and
+// reprocesses the config whenever it changes, on SIGHUP, or once
+// immediately at startup.
+//
+// It watches the directory rather than the file itself because Kubernetes
+// projected ConfigMap volumes update by atomically swapping a "..data"
+// symlink to a new timestamped directory rather than modifying the config
+// file in place - a poller comparing the file's ModTime, or a watch on the
+// file itself, can miss that swap entirely.
+func runWatchMode() {
+	log.Println("Running in WATCH MODE - will monitor config file for changes")
+
+	configPath := getConfigPath()
+	configDir := filepath.Dir(configPath)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Fatalf("Failed to create config watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(configDir); err != nil {
+		log.Fatalf("Failed to watch config directory %s: %v", configDir, err)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	reload("Starting up")
+
+	var debounce *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			log.Printf("Config directory event: %s", event)
+			if debounce == nil {
+				debounce = time.AfterFunc(reloadDebounce, func() { reload("Config directory changed") })
+			} else {
+				debounce.Reset(reloadDebounce)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watcher error: %v", err)
+
+		case <-sighup:
+			reload("Received SIGHUP")
+		}
+	}
+}
+
+func reload(reason string) {
+	log.Printf("%s, reprocessing...", reason)
+
+	config, err := loadConfig()
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
+		return
+	}
+
+	if err := processConfig(config); err != nil {
+		log.Printf("Failed to process config: %v", err)
+	} else {
+		log.Println("Config processed successfully")
+	}
+}